@@ -3,13 +3,10 @@ package carwings
 import (
 	"bytes"
 	"encoding/base64"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
-	"net/http/httputil"
 	"net/url"
-	"os"
 	"time"
 
 	"golang.org/x/crypto/blowfish"
@@ -35,9 +32,6 @@ var (
 	// ErrUpdateFailed indicates an error talking to the Carwings
 	// service when fetching updated vehicle data.
 	ErrUpdateFailed = errors.New("failed to retrieve updated info from vehicle")
-
-	// Debug indiciates whether to log HTTP responses to stderr
-	Debug = false
 )
 
 func pkcs5Padding(data []byte, blocksize int) []byte {
@@ -85,10 +79,23 @@ const (
 
 // Session defines a one or more connections to the Carwings service
 type Session struct {
+	backend         Backend
 	region          string
 	vin             string
 	customSessionID string
 	loc             *time.Location
+	vehicles        []Vehicle
+
+	// httpClient is used for requests against the legacy backend;
+	// userAgent is sent to either backend.  See ConnectOptions.
+	httpClient *http.Client
+	userAgent  string
+	retry      RetryPolicy
+	logger     Logger
+
+	// kamereonClient is an OAuth2-authenticated HTTP client used
+	// when backend is BackendKamereon.  Nil for BackendLegacy.
+	kamereonClient *http.Client
 }
 
 // BatteryStatus contains information about the vehicle's state of
@@ -244,37 +251,22 @@ func (r *baseResponse) Status() int {
 	return r.StatusCode
 }
 
-func apiRequest(endpoint string, params url.Values, target response) error {
-	resp, err := http.PostForm(baseURL+endpoint, params)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if Debug {
-		body, err := httputil.DumpResponse(resp, true)
-		if err != nil {
-			panic(err)
-		}
-		fmt.Fprintln(os.Stderr, string(body))
-		fmt.Fprintln(os.Stderr)
-	}
-
-	dec := json.NewDecoder(resp.Body)
-	if err := dec.Decode(target); err != nil {
-		return err
-	}
-
-	if s := target.Status(); s != http.StatusOK {
-		return fmt.Errorf("received status code %d", s)
+// Connect establishes a new authenticated Session with the Carwings
+// service.  By default it uses http.DefaultClient; pass a
+// ConnectOptions to customize the HTTP client, user agent, or retry
+// behavior.
+func Connect(username, password, region string, opts ...ConnectOptions) (*Session, error) {
+	co := resolveConnectOptions(opts)
+
+	s := &Session{
+		backend:    BackendLegacy,
+		region:     region,
+		httpClient: co.HTTPClient,
+		userAgent:  co.UserAgent,
+		retry:      co.RetryPolicy,
+		logger:     co.Logger,
 	}
 
-	return nil
-}
-
-// Connect establishes a new authenticated Session with the Carwings
-// service.
-func Connect(username, password, region string) (*Session, error) {
 	params := url.Values{}
 	params.Set("initial_app_strings", initialAppStrings)
 
@@ -283,7 +275,7 @@ func Connect(username, password, region string) (*Session, error) {
 		Message string `json:"message"`
 		Baseprm string `json:"baseprm"`
 	}
-	if err := apiRequest("InitialApp.php", params, &initResp); err != nil {
+	if err := s.apiRequest("InitialApp.php", params, &initResp); err != nil {
 		return nil, err
 	}
 
@@ -303,6 +295,8 @@ func Connect(username, password, region string) (*Session, error) {
 		VehicleInfoList struct {
 			VehicleInfo []struct {
 				VIN             string `json:"vin"`
+				Nickname        string `json:"nickname"`
+				ModelYear       string `json:"modelYear"`
 				CustomSessionID string `json:"custom_sessionid"`
 			} `json:"vehicleInfo"`
 		}
@@ -311,22 +305,34 @@ func Connect(username, password, region string) (*Session, error) {
 			Timezone string
 		}
 	}
-	if err := apiRequest("UserLoginRequest.php", params, &loginResp); err != nil {
+	if err := s.apiRequest("UserLoginRequest.php", params, &loginResp); err != nil {
 		return nil, err
 	}
 
+	if len(loginResp.VehicleInfoList.VehicleInfo) == 0 {
+		return nil, fmt.Errorf("carwings: account has no vehicles")
+	}
+
 	loc, err := time.LoadLocation(loginResp.CustomerInfo.Timezone)
 	if err != nil {
 		loc = time.UTC
 	}
-	vi := loginResp.VehicleInfoList.VehicleInfo[0]
-
-	return &Session{
-		region:          region,
-		vin:             vi.VIN,
-		customSessionID: vi.CustomSessionID,
-		loc:             loc,
-	}, nil
+
+	vehicles := make([]Vehicle, len(loginResp.VehicleInfoList.VehicleInfo))
+	for i, vi := range loginResp.VehicleInfoList.VehicleInfo {
+		vehicles[i] = Vehicle{
+			VIN:             vi.VIN,
+			Nickname:        vi.Nickname,
+			ModelYear:       vi.ModelYear,
+			CustomSessionID: vi.CustomSessionID,
+		}
+	}
+
+	s.loc = loc
+	s.vehicles = vehicles
+	s.selectVehicle(vehicles[0])
+
+	return s, nil
 }
 
 func (s *Session) commonParams() url.Values {
@@ -342,6 +348,10 @@ func (s *Session) commonParams() url.Values {
 // "result key" that can be used to poll for status with the
 // CheckUpdate method.
 func (s *Session) UpdateStatus() (string, error) {
+	if s.backend == BackendKamereon {
+		return s.kamereonUpdateStatus()
+	}
+
 	if s.customSessionID == "" {
 		return "", ErrNotLoggedIn
 	}
@@ -350,7 +360,7 @@ func (s *Session) UpdateStatus() (string, error) {
 		baseResponse
 		ResultKey string `json:"resultKey"`
 	}
-	if err := apiRequest("BatteryStatusCheckRequest.php", s.commonParams(), &resp); err != nil {
+	if err := s.apiRequest("BatteryStatusCheckRequest.php", s.commonParams(), &resp); err != nil {
 		return "", err
 	}
 
@@ -360,6 +370,10 @@ func (s *Session) UpdateStatus() (string, error) {
 // CheckUpdate returns whether the update corresponding to the
 // provided result key has finished.
 func (s *Session) CheckUpdate(resultKey string) (bool, error) {
+	if s.backend == BackendKamereon {
+		return s.kamereonCheckUpdate(resultKey)
+	}
+
 	if s.customSessionID == "" {
 		return false, ErrNotLoggedIn
 	}
@@ -373,7 +387,7 @@ func (s *Session) CheckUpdate(resultKey string) (bool, error) {
 		OperationResult string `json:"operationResult"`
 	}
 
-	if err := apiRequest("BatteryStatusCheckResultRequest.php", params, &resp); err != nil {
+	if err := s.apiRequest("BatteryStatusCheckResultRequest.php", params, &resp); err != nil {
 		return false, err
 	}
 
@@ -390,6 +404,10 @@ func (s *Session) CheckUpdate(resultKey string) (bool, error) {
 // cached from the last time the vehicle data was updated.  Use
 // UpdateStatus method to update vehicle data.
 func (s *Session) BatteryStatus() (BatteryStatus, error) {
+	if s.backend == BackendKamereon {
+		return s.kamereonBatteryStatus()
+	}
+
 	if s.customSessionID == "" {
 		return BatteryStatus{}, ErrNotLoggedIn
 	}
@@ -424,7 +442,7 @@ func (s *Session) BatteryStatus() (BatteryStatus, error) {
 			NotificationDateAndTime cwTime
 		}
 	}
-	if err := apiRequest("BatteryStatusRecordsRequest.php", s.commonParams(), &resp); err != nil {
+	if err := s.apiRequest("BatteryStatusRecordsRequest.php", s.commonParams(), &resp); err != nil {
 		return BatteryStatus{}, err
 	}
 
@@ -447,28 +465,3 @@ func (s *Session) BatteryStatus() (BatteryStatus, error) {
 
 	return bs, nil
 }
-
-// ClimateControlStatus returns the most recent climate control status
-// from the Carwings service.  Note that this data is not real-time:
-// it is cached from the last time the vehicle data was updated.  Use
-// UpdateStatus method to update vehicle data.
-func (s *Session) ClimateControlStatus() error {
-	if s.customSessionID == "" {
-		return ErrNotLoggedIn
-	}
-
-	var resp struct {
-		baseResponse
-	}
-
-	oldDebug := Debug
-	Debug = true
-
-	if err := apiRequest("RemoteACRecordsRequest.php", s.commonParams(), &resp); err != nil {
-		return err
-	}
-
-	Debug = oldDebug
-
-	return nil
-}
\ No newline at end of file
@@ -0,0 +1,176 @@
+package carwings
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// defaultUserAgent is sent when ConnectOptions.UserAgent is not set.
+const defaultUserAgent = "carwings (https://github.com/winterhalder/carwings)"
+
+// RetryPolicy controls the exponential backoff used by WaitForUpdate
+// while it waits for the vehicle to respond.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times to poll before
+	// giving up. Zero means use DefaultRetryPolicy's value.
+	MaxAttempts int
+
+	// InitialInterval is how long to wait before the first retry.
+	// Zero means use DefaultRetryPolicy's value.
+	InitialInterval time.Duration
+
+	// Multiplier scales InitialInterval after each attempt. Zero
+	// means use DefaultRetryPolicy's value.
+	Multiplier float64
+}
+
+// DefaultRetryPolicy is used by Connect and ConnectKamereon when
+// ConnectOptions.RetryPolicy is the zero value.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:     10,
+	InitialInterval: 2 * time.Second,
+	Multiplier:      1.5,
+}
+
+func (r RetryPolicy) withDefaults() RetryPolicy {
+	if r.MaxAttempts == 0 {
+		r.MaxAttempts = DefaultRetryPolicy.MaxAttempts
+	}
+	if r.InitialInterval == 0 {
+		r.InitialInterval = DefaultRetryPolicy.InitialInterval
+	}
+	if r.Multiplier == 0 {
+		r.Multiplier = DefaultRetryPolicy.Multiplier
+	}
+	return r
+}
+
+// ConnectOptions customizes the behavior of Connect and
+// ConnectKamereon.
+type ConnectOptions struct {
+	// HTTPClient is used for all requests. Defaults to
+	// http.DefaultClient.
+	HTTPClient *http.Client
+
+	// UserAgent is sent with every request. Defaults to
+	// defaultUserAgent.
+	UserAgent string
+
+	// RetryPolicy controls WaitForUpdate's backoff. Defaults to
+	// DefaultRetryPolicy.
+	RetryPolicy RetryPolicy
+
+	// Logger receives diagnostic output, with account and vehicle
+	// identifiers scrubbed by Redact. Defaults to a no-op Logger,
+	// so the package stays quiet unless a caller opts in.
+	Logger Logger
+}
+
+func (o ConnectOptions) withDefaults() ConnectOptions {
+	if o.HTTPClient == nil {
+		o.HTTPClient = http.DefaultClient
+	}
+	if o.UserAgent == "" {
+		o.UserAgent = defaultUserAgent
+	}
+	o.RetryPolicy = o.RetryPolicy.withDefaults()
+	if o.Logger == nil {
+		o.Logger = noopLogger{}
+	}
+	return o
+}
+
+// resolveConnectOptions returns opts[0] with defaults filled in, or
+// the zero ConnectOptions with defaults filled in if opts is empty.
+// Connect and ConnectKamereon accept opts as a variadic parameter
+// purely so callers who don't need it can omit it entirely.
+func resolveConnectOptions(opts []ConnectOptions) ConnectOptions {
+	if len(opts) > 0 {
+		return opts[0].withDefaults()
+	}
+	return ConnectOptions{}.withDefaults()
+}
+
+// apiRequest performs a POST against the legacy Carwings gateway
+// using s.httpClient and s.userAgent.
+func (s *Session) apiRequest(endpoint string, params url.Values, target response) error {
+	req, err := http.NewRequest(http.MethodPost, baseURL+endpoint, strings.NewReader(params.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", s.userAgent)
+
+	s.logger.Debugf("%s %s", req.Method, Redact(req.URL.String()))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if body, err := httputil.DumpResponse(resp, true); err == nil {
+		s.logger.Debugf("%s", Redact(string(body)))
+	}
+
+	dec := json.NewDecoder(resp.Body)
+	if err := dec.Decode(target); err != nil {
+		return err
+	}
+
+	if st := target.Status(); st != http.StatusOK {
+		return fmt.Errorf("received status code %d", st)
+	}
+
+	return nil
+}
+
+// WaitForUpdate asks the vehicle for fresh data and blocks until it
+// arrives, retrying on ErrUpdateFailed with exponential backoff
+// according to s's RetryPolicy (see ConnectOptions). It honors ctx
+// cancellation, so callers no longer need to hand-roll the
+// UpdateStatus/CheckUpdate polling loop themselves.
+func (s *Session) WaitForUpdate(ctx context.Context) error {
+	resultKey, err := s.UpdateStatus()
+	if err != nil {
+		return err
+	}
+
+	retry := s.retry.withDefaults()
+	interval := retry.InitialInterval
+
+	for attempt := 0; attempt < retry.MaxAttempts; attempt++ {
+		done, err := s.CheckUpdate(resultKey)
+		if err != nil && err != ErrUpdateFailed {
+			return err
+		}
+		if done && err == nil {
+			return nil
+		}
+
+		s.logger.Warnf("waiting for vehicle update, attempt %d/%d, retrying in %s", attempt+1, retry.MaxAttempts, interval)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+
+		// A failed update will not complete; request a new one.
+		if err == ErrUpdateFailed {
+			if resultKey, err = s.UpdateStatus(); err != nil {
+				return err
+			}
+		}
+
+		interval = time.Duration(float64(interval) * retry.Multiplier)
+	}
+
+	return ErrUpdateFailed
+}
@@ -0,0 +1,162 @@
+package carwings
+
+import "time"
+
+// ClimateStatus describes the vehicle's remote climate control (AC)
+// state, as last reported by RemoteACRecordsRequest.php.
+//
+// On BackendKamereon it is built from the hvac-status endpoint: only
+// Running is set, plus StartTime (if running) or StopTime (if not)
+// holding the time of that status update.  PluginState and
+// BatteryRemainingAmount are left zero.
+type ClimateStatus struct {
+	// Running is true if the climate control system is currently
+	// on.
+	Running bool
+
+	// StartTime is when climate control was last turned on.
+	StartTime time.Time
+
+	// StopTime is when climate control was last turned off, or is
+	// scheduled to turn off if Running is true.
+	StopTime time.Time
+
+	// PluginState is the vehicle's plugged-in state at the time of
+	// this record.
+	PluginState PluginState
+
+	// BatteryRemainingAmount is the remaining battery level at the
+	// time of this record.  Units unknown, same as
+	// BatteryStatus.Remaining.
+	BatteryRemainingAmount int
+}
+
+// ClimateControlStatus returns the most recent climate control status
+// from the Carwings service.  Note that this data is not real-time:
+// it is cached from the last time the vehicle data was updated.  Use
+// UpdateStatus method to update vehicle data.
+func (s *Session) ClimateControlStatus() (ClimateStatus, error) {
+	if s.backend == BackendKamereon {
+		return s.kamereonClimateControlStatus()
+	}
+
+	if s.customSessionID == "" {
+		return ClimateStatus{}, ErrNotLoggedIn
+	}
+
+	var resp struct {
+		baseResponse
+		RemoteACRecords struct {
+			RemoteACOperation      string `json:"RemoteACOperation"`
+			ACStartStopDateAndTime cwTime `json:"ACStartStopDateAndTime"`
+			ACStopDateAndTime      cwTime `json:"ACStopDateAndTime"`
+			PluginState            string `json:"PluginState"`
+			BatteryRemainingAmount int    `json:"BatteryRemainingAmount,string"`
+		} `json:"RemoteACRecords"`
+	}
+	if err := s.apiRequest("RemoteACRecordsRequest.php", s.commonParams(), &resp); err != nil {
+		return ClimateStatus{}, err
+	}
+
+	rec := resp.RemoteACRecords
+	return ClimateStatus{
+		Running:                rec.RemoteACOperation == start,
+		StartTime:              time.Time(rec.ACStartStopDateAndTime).In(s.loc),
+		StopTime:               time.Time(rec.ACStopDateAndTime).In(s.loc),
+		PluginState:            PluginState(rec.PluginState),
+		BatteryRemainingAmount: rec.BatteryRemainingAmount,
+	}, nil
+}
+
+// ClimateOn asks the vehicle to turn on its climate control system.
+// This is an asynchronous operation: it returns a "result key" that
+// can be used to poll for status with the CheckClimate method.
+func (s *Session) ClimateOn() (string, error) {
+	if s.backend == BackendKamereon {
+		return s.kamereonHVACAction("start")
+	}
+
+	if s.customSessionID == "" {
+		return "", ErrNotLoggedIn
+	}
+
+	var resp struct {
+		baseResponse
+		ResultKey string `json:"resultKey"`
+	}
+	if err := s.apiRequest("ACRemoteRequest.php", s.commonParams(), &resp); err != nil {
+		return "", err
+	}
+
+	return resp.ResultKey, nil
+}
+
+// ClimateOff asks the vehicle to turn off its climate control system.
+// This is an asynchronous operation: it returns a "result key" that
+// can be used to poll for status with the CheckClimate method.
+func (s *Session) ClimateOff() (string, error) {
+	if s.backend == BackendKamereon {
+		return s.kamereonHVACAction("stop")
+	}
+
+	if s.customSessionID == "" {
+		return "", ErrNotLoggedIn
+	}
+
+	var resp struct {
+		baseResponse
+		ResultKey string `json:"resultKey"`
+	}
+	if err := s.apiRequest("ACRemoteOffRequest.php", s.commonParams(), &resp); err != nil {
+		return "", err
+	}
+
+	return resp.ResultKey, nil
+}
+
+// CheckClimate returns whether the ClimateOn or ClimateOff operation
+// corresponding to the provided result key has finished.
+func (s *Session) CheckClimate(resultKey string) (bool, error) {
+	if s.backend == BackendKamereon {
+		return true, nil
+	}
+
+	if s.customSessionID == "" {
+		return false, ErrNotLoggedIn
+	}
+
+	params := s.commonParams()
+	params.Set("resultKey", resultKey)
+
+	var resp struct {
+		baseResponse
+		ResponseFlag    int    `json:"responseFlag,string"`
+		OperationResult string `json:"operationResult"`
+	}
+	if err := s.apiRequest("ACRemoteResultRequest.php", params, &resp); err != nil {
+		return false, err
+	}
+
+	var err error
+	if resp.OperationResult == electricWaveAbnormal {
+		err = ErrUpdateFailed
+	}
+
+	return resp.ResponseFlag == 1, err
+}
+
+// ChargingRequest asks the vehicle to begin charging immediately.
+// Unlike UpdateStatus and ClimateOn/ClimateOff, this is fire-and-forget:
+// the service does not hand back a result key to poll.
+func (s *Session) ChargingRequest() error {
+	if s.backend == BackendKamereon {
+		return s.kamereonChargingRequest()
+	}
+
+	if s.customSessionID == "" {
+		return ErrNotLoggedIn
+	}
+
+	var resp baseResponse
+	return s.apiRequest("BatteryRemoteChargingRequest.php", s.commonParams(), &resp)
+}
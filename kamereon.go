@@ -0,0 +1,508 @@
+package carwings
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// Backend selects which Nissan service a Session talks to.
+type Backend string
+
+const (
+	// BackendLegacy talks to the original Carwings gateway
+	// (gdcportalgw.its-mo.com).  This is required for pre-2018
+	// vehicles and is the default used by Connect.
+	BackendLegacy = Backend("legacy")
+
+	// BackendKamereon talks to the newer Kamereon/NissanConnect EV
+	// platform used by post-2018 vehicles.  Use ConnectKamereon to
+	// create a Session with this backend.
+	BackendKamereon = Backend("kamereon")
+)
+
+const (
+	kamereonRealm      = "a-ncb-prod"
+	kamereonClientID   = "a-ncb-prod-android"
+	kamereonSecret     = "gdfrhuuzgzuyzrevgvgzuzrevguz" // extracted from the NissanConnect EV app
+	kamereonScope      = "openid profile vehicles"
+	kamereonRedirect   = "org.kamereon.service.nci:/oauth2redirect"
+	kamereonAPIVersion = "protocol=1.0,resource=2.1"
+)
+
+// kamereonRegionCode maps a carwings region code to the region
+// segment used in Kamereon hostnames, e.g. "eu" or "nna". Note this
+// is combined differently depending on the host: auth hosts are
+// "prod.<region>.auth.kamereon.org", while data hosts are
+// "<service>-prod.apps.<region>.kamereon.io".
+func kamereonRegionCode(region string) string {
+	switch region {
+	case RegionUSA:
+		return "nna"
+	case RegionCanada:
+		return "nci"
+	case RegionAustralia:
+		return "nma"
+	case RegionJapan:
+		return "nml"
+	case RegionEurope:
+		fallthrough
+	default:
+		return "eu"
+	}
+}
+
+// kamereonAMBaseURL returns the root of the ForgeRock Access Management
+// instance backing Kamereon auth, e.g. "https://prod.eu.auth.kamereon.org/kauth".
+// The JSON auth tree and the OAuth2 endpoints are both mounted directly
+// under this root as siblings (".../json/realms/..." and
+// ".../oauth2/realms/..."), not nested inside each other.
+func kamereonAMBaseURL(region string) string {
+	return fmt.Sprintf("https://prod.%s.auth.kamereon.org/kauth", kamereonRegionCode(region))
+}
+
+func kamereonAuthBaseURL(region string) string {
+	return fmt.Sprintf("%s/json/realms/root/realms/%s", kamereonAMBaseURL(region), kamereonRealm)
+}
+
+func kamereonCarAdapterURL(region string) string {
+	return fmt.Sprintf("https://alliance-platform-caradapter-prod.apps.%s.kamereon.io/car-adapter/v1", kamereonRegionCode(region))
+}
+
+func kamereonBFFWebURL(region string) string {
+	return fmt.Sprintf("https://nci-bff-web-prod.apps.%s.kamereon.io/bff-web/v2", kamereonRegionCode(region))
+}
+
+func kamereonOAuthConfig(region string) *oauth2.Config {
+	base := kamereonAMBaseURL(region)
+	return &oauth2.Config{
+		ClientID:     kamereonClientID,
+		ClientSecret: kamereonSecret,
+		Scopes:       strings.Split(kamereonScope, " "),
+		RedirectURL:  kamereonRedirect,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  base + "/oauth2/realms/root/realms/" + kamereonRealm + "/authorize",
+			TokenURL: base + "/oauth2/realms/root/realms/" + kamereonRealm + "/access_token",
+		},
+	}
+}
+
+// kamereonCallback is one entry of the ForgeRock "callbacks" array used
+// by the authenticate endpoint to collect the username and password.
+type kamereonCallback struct {
+	Type   string `json:"type"`
+	Output []struct {
+		Name  string      `json:"name"`
+		Value interface{} `json:"value"`
+	} `json:"output"`
+	Input []struct {
+		Name  string      `json:"name"`
+		Value interface{} `json:"value"`
+	} `json:"input"`
+}
+
+type kamereonAuthStep struct {
+	AuthID    string             `json:"authId"`
+	Callbacks []kamereonCallback `json:"callbacks"`
+	TokenID   string             `json:"tokenId"`
+}
+
+// kamereonAuthenticate runs the ForgeRock authentication tree: a first
+// POST with no body to obtain an authId and the callback shapes, then a POST with
+// username and password filled in to obtain a tokenId.
+func kamereonAuthenticate(client *http.Client, userAgent, region, username, password string) (string, error) {
+	req, err := http.NewRequest(http.MethodPost, kamereonAuthBaseURL(region)+"/authenticate", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept-API-Version", "resource=2.1, protocol=1.0")
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var step kamereonAuthStep
+	if err := json.NewDecoder(resp.Body).Decode(&step); err != nil {
+		return "", err
+	}
+
+	for i, cb := range step.Callbacks {
+		if cb.Type == "NameCallback" {
+			step.Callbacks[i].Input[0].Value = username
+		}
+		if cb.Type == "PasswordCallback" {
+			step.Callbacks[i].Input[0].Value = password
+		}
+	}
+
+	body, err := json.Marshal(step)
+	if err != nil {
+		return "", err
+	}
+
+	req, err = http.NewRequest(http.MethodPost, kamereonAuthBaseURL(region)+"/authenticate", strings.NewReader(string(body)))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept-API-Version", "resource=2.1, protocol=1.0")
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err = client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(&step); err != nil {
+		return "", err
+	}
+	if step.TokenID == "" {
+		return "", fmt.Errorf("carwings: kamereon authentication did not return a tokenId")
+	}
+
+	return step.TokenID, nil
+}
+
+// kamereonAuthorizationCode exchanges a tokenId (an SSO session) for
+// an OAuth2 authorization code via the standard /authorize endpoint.
+func kamereonAuthorizationCode(client *http.Client, userAgent string, cfg *oauth2.Config, tokenID string) (string, error) {
+	u := cfg.AuthCodeURL("carwings", oauth2.SetAuthURLParam("scope", kamereonScope))
+
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Cookie", "i-sso-token="+tokenID)
+	req.Header.Set("User-Agent", userAgent)
+
+	client = &http.Client{
+		Transport: client.Transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	loc, err := resp.Location()
+	if err != nil {
+		return "", fmt.Errorf("carwings: kamereon authorize did not redirect: %w", err)
+	}
+
+	code := loc.Query().Get("code")
+	if code == "" {
+		return "", fmt.Errorf("carwings: kamereon authorize redirect did not contain a code")
+	}
+
+	return code, nil
+}
+
+// ConnectKamereon establishes a new authenticated Session with the
+// Kamereon/NissanConnect EV service, used by post-2018 vehicles.  The
+// returned Session implements the same methods as one returned by
+// Connect.
+func ConnectKamereon(username, password, region string, opts ...ConnectOptions) (*Session, error) {
+	co := resolveConnectOptions(opts)
+
+	tokenID, err := kamereonAuthenticate(co.HTTPClient, co.UserAgent, region, username, password)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := kamereonOAuthConfig(region)
+
+	code, err := kamereonAuthorizationCode(co.HTTPClient, co.UserAgent, cfg, tokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, co.HTTPClient)
+	token, err := cfg.Exchange(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Session{
+		backend:        BackendKamereon,
+		region:         region,
+		loc:            time.UTC,
+		userAgent:      co.UserAgent,
+		retry:          co.RetryPolicy,
+		logger:         co.Logger,
+		kamereonClient: oauth2.NewClient(ctx, cfg.TokenSource(ctx, token)),
+	}
+
+	if err := s.kamereonLoadVehicles(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// kamereonGet performs an authenticated GET against the Kamereon API
+// and decodes the JSON response body into target.
+func (s *Session) kamereonGet(endpoint string, target interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("apiVersion", kamereonAPIVersion)
+	req.Header.Set("User-Agent", s.userAgent)
+
+	s.logger.Debugf("GET %s", Redact(endpoint, s.vin))
+
+	resp, err := s.kamereonClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("received status code %d", resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(target)
+}
+
+// kamereonPost performs an authenticated POST with a JSON body against
+// the Kamereon API.
+func (s *Session) kamereonPost(endpoint string, body interface{}, target interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(string(b)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("apiVersion", kamereonAPIVersion)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", s.userAgent)
+
+	s.logger.Debugf("POST %s %s", Redact(endpoint, s.vin), Redact(string(b), s.vin))
+
+	resp, err := s.kamereonClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("received status code %d", resp.StatusCode)
+	}
+
+	if target == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(target)
+}
+
+type kamereonCarsResponse struct {
+	Data []struct {
+		VIN       string `json:"vin"`
+		Nickname  string `json:"nickname"`
+		ModelYear string `json:"modelYear"`
+	} `json:"data"`
+}
+
+func (s *Session) kamereonLoadVehicles() error {
+	var carsResp kamereonCarsResponse
+	u, err := url.Parse(kamereonBFFWebURL(s.region) + "/users/me/cars")
+	if err != nil {
+		return err
+	}
+	if err := s.kamereonGet(u.String(), &carsResp); err != nil {
+		return err
+	}
+	if len(carsResp.Data) == 0 {
+		return fmt.Errorf("carwings: kamereon account has no vehicles")
+	}
+
+	s.vehicles = make([]Vehicle, len(carsResp.Data))
+	for i, car := range carsResp.Data {
+		s.vehicles[i] = Vehicle{
+			VIN:       car.VIN,
+			Nickname:  car.Nickname,
+			ModelYear: car.ModelYear,
+		}
+	}
+	s.selectVehicle(s.vehicles[0])
+
+	return nil
+}
+
+type kamereonBatteryStatusResponse struct {
+	Data struct {
+		Attributes struct {
+			LastUpdateTime  string `json:"lastUpdateTime"`
+			BatteryAutonomy int    `json:"batteryAutonomy"`
+			BatteryCapacity int    `json:"batteryCapacity"`
+			BatteryLevel    int    `json:"batteryLevel"`
+			PlugStatus      int    `json:"plugStatus"`
+			ChargingStatus  string `json:"chargingStatus"`
+			RemainingTime   int    `json:"remainingTime"`
+			ChargePower     int    `json:"chargePower"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// kamereonPluginState translates the Kamereon plugStatus integer into
+// the carwings PluginState used by the legacy backend.
+func kamereonPluginState(plugStatus int) PluginState {
+	if plugStatus == 0 {
+		return NotConnected
+	}
+	return Connected
+}
+
+// kamereonChargingStatus translates the Kamereon chargingStatus string
+// into the carwings ChargingStatus used by the legacy backend.
+func kamereonChargingStatus(status string) ChargingStatus {
+	switch status {
+	case "0.0", "-1.0":
+		return NotCharging
+	default:
+		return NormalCharging
+	}
+}
+
+func (s *Session) kamereonBatteryStatus() (BatteryStatus, error) {
+	var resp kamereonBatteryStatusResponse
+	endpoint := fmt.Sprintf("%s/cars/%s/battery-status", kamereonCarAdapterURL(s.region), s.vin)
+	if err := s.kamereonGet(endpoint, &resp); err != nil {
+		return BatteryStatus{}, err
+	}
+
+	attrs := resp.Data.Attributes
+	ts, err := time.Parse(time.RFC3339, attrs.LastUpdateTime)
+	if err != nil {
+		return BatteryStatus{}, err
+	}
+
+	return BatteryStatus{
+		Timestamp:          ts,
+		Capacity:           attrs.BatteryCapacity,
+		Remaining:          attrs.BatteryLevel,
+		StateOfCharge:      attrs.BatteryLevel,
+		CruisingRangeACOn:  attrs.BatteryAutonomy * 1000,
+		CruisingRangeACOff: attrs.BatteryAutonomy * 1000,
+		PluginState:        kamereonPluginState(attrs.PlugStatus),
+		ChargingStatus:     kamereonChargingStatus(attrs.ChargingStatus),
+		TimeToFull: TimeToFull{
+			Level2: time.Duration(attrs.RemainingTime) * time.Minute,
+		},
+	}, nil
+}
+
+// kamereonUpdateStatus asks the vehicle for fresh data.  Kamereon does
+// this synchronously from the caller's point of view (the platform
+// polls the vehicle internally), so the "result key" is just the VIN;
+// kamereonCheckUpdate always reports completion immediately.
+func (s *Session) kamereonUpdateStatus() (string, error) {
+	endpoint := fmt.Sprintf("%s/cars/%s/actions/refresh-battery-status", kamereonCarAdapterURL(s.region), s.vin)
+	if err := s.kamereonPost(endpoint, map[string]interface{}{
+		"data": map[string]interface{}{
+			"type": "RefreshBatteryStatus",
+		},
+	}, nil); err != nil {
+		return "", err
+	}
+
+	return s.vin, nil
+}
+
+func (s *Session) kamereonCheckUpdate(resultKey string) (bool, error) {
+	return true, nil
+}
+
+type kamereonHVACStatusResponse struct {
+	Data struct {
+		Attributes struct {
+			HvacStatus     string `json:"hvacStatus"`
+			LastUpdateTime string `json:"lastUpdateTime"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+func (s *Session) kamereonClimateControlStatus() (ClimateStatus, error) {
+	var resp kamereonHVACStatusResponse
+	endpoint := fmt.Sprintf("%s/cars/%s/hvac-status", kamereonCarAdapterURL(s.region), s.vin)
+	if err := s.kamereonGet(endpoint, &resp); err != nil {
+		return ClimateStatus{}, err
+	}
+
+	attrs := resp.Data.Attributes
+	ts, err := time.Parse(time.RFC3339, attrs.LastUpdateTime)
+	if err != nil {
+		return ClimateStatus{}, err
+	}
+
+	cs := ClimateStatus{Running: attrs.HvacStatus == "on"}
+	if cs.Running {
+		cs.StartTime = ts
+	} else {
+		cs.StopTime = ts
+	}
+
+	return cs, nil
+}
+
+// kamereonHVACAction starts or stops the climate control system.  As
+// with kamereonUpdateStatus, Kamereon performs this synchronously, so
+// the returned "result key" is just the VIN.
+func (s *Session) kamereonHVACAction(action string) (string, error) {
+	endpoint := fmt.Sprintf("%s/cars/%s/actions/hvac-start", kamereonCarAdapterURL(s.region), s.vin)
+	if err := s.kamereonPost(endpoint, map[string]interface{}{
+		"data": map[string]interface{}{
+			"type": "HvacStart",
+			"attributes": map[string]interface{}{
+				"action": action,
+			},
+		},
+	}, nil); err != nil {
+		return "", err
+	}
+
+	return s.vin, nil
+}
+
+func (s *Session) kamereonChargingRequest() error {
+	endpoint := fmt.Sprintf("%s/cars/%s/actions/charging-start", kamereonCarAdapterURL(s.region), s.vin)
+	return s.kamereonPost(endpoint, map[string]interface{}{
+		"data": map[string]interface{}{
+			"type": "ChargingStart",
+			"attributes": map[string]interface{}{
+				"action": "start",
+			},
+		},
+	}, nil)
+}
+
+// kamereonChargingSchedule and kamereonSetChargingSchedule exist so
+// ChargingSchedule/SetChargingSchedule can be called on a Kamereon
+// Session without a type switch at the call site, but the Kamereon
+// car-adapter charging-schedule endpoints haven't been reverse
+// engineered yet.
+func (s *Session) kamereonChargingSchedule() (ChargingSchedule, error) {
+	return ChargingSchedule{}, fmt.Errorf("carwings: charging schedule is not yet supported on the Kamereon backend")
+}
+
+func (s *Session) kamereonSetChargingSchedule(cs ChargingSchedule) error {
+	return fmt.Errorf("carwings: charging schedule is not yet supported on the Kamereon backend")
+}
@@ -0,0 +1,22 @@
+package carwings
+
+import "testing"
+
+// TestKamereonOAuthConfig guards against the AuthURL/TokenURL being
+// built from kamereonAuthBaseURL (the JSON auth-tree base), which
+// would nest the oauth2 endpoints under "kauth/json/realms/..."
+// instead of mounting them as siblings of it directly under the AM
+// root.
+func TestKamereonOAuthConfig(t *testing.T) {
+	cfg := kamereonOAuthConfig(RegionEurope)
+
+	const wantAuthURL = "https://prod.eu.auth.kamereon.org/kauth/oauth2/realms/root/realms/a-ncb-prod/authorize"
+	const wantTokenURL = "https://prod.eu.auth.kamereon.org/kauth/oauth2/realms/root/realms/a-ncb-prod/access_token"
+
+	if cfg.Endpoint.AuthURL != wantAuthURL {
+		t.Errorf("AuthURL = %q, want %q", cfg.Endpoint.AuthURL, wantAuthURL)
+	}
+	if cfg.Endpoint.TokenURL != wantTokenURL {
+		t.Errorf("TokenURL = %q, want %q", cfg.Endpoint.TokenURL, wantTokenURL)
+	}
+}
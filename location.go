@@ -0,0 +1,148 @@
+package carwings
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Location describes the last known GPS position reported by a
+// vehicle.
+type Location struct {
+	// Date and time this location was recorded by the vehicle.
+	Timestamp time.Time
+
+	// Latitude in degrees.
+	Latitude float64
+
+	// Longitude in degrees.
+	Longitude float64
+
+	// Heading in compass degrees, 0-359, where known.
+	Heading int
+}
+
+// LocateVehicle asks the vehicle for its last known GPS position.  On
+// the legacy backend this is an asynchronous operation under the
+// hood: LocateVehicle polls for the result itself and blocks until it
+// is available, retrying on ErrUpdateFailed with exponential backoff
+// according to s's RetryPolicy (see ConnectOptions), the same way
+// WaitForUpdate does for UpdateStatus/CheckUpdate. It honors ctx
+// cancellation.
+func (s *Session) LocateVehicle(ctx context.Context) (Location, error) {
+	if s.backend == BackendKamereon {
+		return s.kamereonLocateVehicle()
+	}
+
+	if s.customSessionID == "" {
+		return Location{}, ErrNotLoggedIn
+	}
+
+	var resp struct {
+		baseResponse
+		ResultKey string `json:"resultKey"`
+	}
+	if err := s.apiRequest("MyCarFinderRequest.php", s.commonParams(), &resp); err != nil {
+		return Location{}, err
+	}
+
+	retry := s.retry.withDefaults()
+	interval := retry.InitialInterval
+
+	for attempt := 0; attempt < retry.MaxAttempts; attempt++ {
+		done, loc, err := s.checkLocate(resp.ResultKey)
+		if err != nil && err != ErrUpdateFailed {
+			return Location{}, err
+		}
+		if done {
+			return loc, nil
+		}
+
+		s.logger.Warnf("waiting for vehicle location, attempt %d/%d, retrying in %s", attempt+1, retry.MaxAttempts, interval)
+
+		select {
+		case <-ctx.Done():
+			return Location{}, ctx.Err()
+		case <-time.After(interval):
+		}
+
+		// A failed request will not complete; send a new one.
+		if err == ErrUpdateFailed {
+			if err := s.apiRequest("MyCarFinderRequest.php", s.commonParams(), &resp); err != nil {
+				return Location{}, err
+			}
+		}
+
+		interval = time.Duration(float64(interval) * retry.Multiplier)
+	}
+
+	return Location{}, ErrUpdateFailed
+}
+
+// checkLocate polls MyCarFinderResultRequest.php once, reporting
+// whether the vehicle has reported its position yet.
+func (s *Session) checkLocate(resultKey string) (bool, Location, error) {
+	params := s.commonParams()
+	params.Set("resultKey", resultKey)
+
+	var resp struct {
+		baseResponse
+		ResponseFlag    int    `json:"responseFlag,string"`
+		OperationResult string `json:"operationResult"`
+		TargetDate      cwTime `json:"targetDate"`
+		TargetPoint     struct {
+			Latitude  float64 `json:"latitude,string"`
+			Longitude float64 `json:"longitude,string"`
+			Heading   int     `json:"heading,string"`
+		} `json:"targetPoint"`
+	}
+	if err := s.apiRequest("MyCarFinderResultRequest.php", params, &resp); err != nil {
+		return false, Location{}, err
+	}
+
+	if resp.OperationResult == electricWaveAbnormal {
+		return false, Location{}, ErrUpdateFailed
+	}
+
+	if resp.ResponseFlag != 1 {
+		return false, Location{}, nil
+	}
+
+	return true, Location{
+		Timestamp: time.Time(resp.TargetDate).In(s.loc),
+		Latitude:  resp.TargetPoint.Latitude,
+		Longitude: resp.TargetPoint.Longitude,
+		Heading:   resp.TargetPoint.Heading,
+	}, nil
+}
+
+func (s *Session) kamereonLocateVehicle() (Location, error) {
+	var resp struct {
+		Data struct {
+			Attributes struct {
+				LastPositionDate string  `json:"lastPositionDate"`
+				GPSLatitude      float64 `json:"gpsLatitude"`
+				GPSLongitude     float64 `json:"gpsLongitude"`
+				Heading          int     `json:"heading"`
+			} `json:"attributes"`
+		} `json:"data"`
+	}
+
+	endpoint := fmt.Sprintf("%s/cars/%s/location", kamereonCarAdapterURL(s.region), s.vin)
+	if err := s.kamereonGet(endpoint, &resp); err != nil {
+		return Location{}, err
+	}
+
+	attrs := resp.Data.Attributes
+	ts, err := time.Parse(time.RFC3339, attrs.LastPositionDate)
+	if err != nil {
+		return Location{}, err
+	}
+
+	return Location{
+		Timestamp: ts,
+		Latitude:  attrs.GPSLatitude,
+		Longitude: attrs.GPSLongitude,
+		Heading:   attrs.Heading,
+	}, nil
+}
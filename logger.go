@@ -0,0 +1,61 @@
+package carwings
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Logger receives diagnostic output from a Session. Pass one via
+// ConnectOptions to see it; the default is silent.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+}
+
+// noopLogger discards everything. It's the default Logger so the
+// package stays quiet unless a caller opts in.
+type noopLogger struct{}
+
+func (noopLogger) Debugf(format string, args ...interface{}) {}
+func (noopLogger) Infof(format string, args ...interface{})  {}
+func (noopLogger) Warnf(format string, args ...interface{})  {}
+
+// redactPatterns matches the form- and JSON-encoded spellings of the
+// fields that identify a specific account or vehicle:
+// UserId/Password (legacy login), custom_sessionid (legacy session
+// token), and VIN/vin (both backends).
+var redactPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)(UserId=)[^&\s"]+`),
+	regexp.MustCompile(`(?i)(Password=)[^&\s"]+`),
+	regexp.MustCompile(`(?i)(custom_sessionid=)[^&\s"]+`),
+	regexp.MustCompile(`(?i)(VIN=)[^&\s"]+`),
+	regexp.MustCompile(`(?i)("(?:userId|password|vin|custom_sessionid)"\s*:\s*")[^"]*(")`),
+}
+
+// Redact scrubs account and vehicle identifiers (UserId, Password,
+// custom_sessionid, VIN) out of a dumped HTTP request or response, the
+// way the evcc Nissan driver does, so it's safe to pass to a Logger.
+//
+// Kamereon endpoints embed the VIN as a bare URL path segment
+// (".../cars/<VIN>/battery-status") rather than as a key=value or
+// JSON field, so no regex above can find it without knowing the
+// value to look for. Callers that have a known sensitive value not
+// already covered by redactPatterns, such as a Session's VIN, should
+// pass it via extra; Redact scrubs every literal occurrence of it.
+func Redact(s string, extra ...string) string {
+	for _, re := range redactPatterns {
+		if re.NumSubexp() == 2 {
+			s = re.ReplaceAllString(s, "${1}REDACTED${2}")
+		} else {
+			s = re.ReplaceAllString(s, "${1}REDACTED")
+		}
+	}
+	for _, v := range extra {
+		if v == "" {
+			continue
+		}
+		s = strings.ReplaceAll(s, v, "REDACTED")
+	}
+	return s
+}
@@ -0,0 +1,42 @@
+package carwings
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestRedactLoginResponse covers a captured UserLoginRequest.php
+// response, which carries the live session token in
+// custom_sessionid.
+func TestRedactLoginResponse(t *testing.T) {
+	const body = `{"status":200,"VehicleInfoList":{"VehicleInfo":[{"VIN":"5N1AZ2MJ9JN123456"}]},` +
+		`"custom_sessionid":"abc123def456","UserId":"someone@example.com"}`
+
+	got := Redact(body)
+
+	for _, secret := range []string{"abc123def456", "5N1AZ2MJ9JN123456", "someone@example.com"} {
+		if strings.Contains(got, secret) {
+			t.Errorf("Redact(%q) = %q, still contains secret %q", body, got, secret)
+		}
+	}
+	if !strings.Contains(got, "REDACTED") {
+		t.Errorf("Redact(%q) = %q, want at least one REDACTED marker", body, got)
+	}
+}
+
+// TestRedactKamereonEndpoint covers a Kamereon car-adapter endpoint,
+// which embeds the VIN as a bare URL path segment rather than a
+// key=value or JSON field.
+func TestRedactKamereonEndpoint(t *testing.T) {
+	const vin = "5N1AZ2MJ9JN123456"
+	endpoint := kamereonCarAdapterURL(RegionEurope) + "/cars/" + vin + "/battery-status"
+
+	got := Redact(endpoint, vin)
+
+	if strings.Contains(got, vin) {
+		t.Errorf("Redact(%q, %q) = %q, still contains VIN", endpoint, vin, got)
+	}
+	if !strings.Contains(got, "REDACTED") {
+		t.Errorf("Redact(%q, %q) = %q, want a REDACTED marker", endpoint, vin, got)
+	}
+}
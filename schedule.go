@@ -0,0 +1,168 @@
+package carwings
+
+import (
+	"fmt"
+	"time"
+)
+
+// Weekdays is a bitmask of days of the week, used to describe which
+// days a ChargingSchedule applies to.
+type Weekdays uint8
+
+const (
+	Sunday Weekdays = 1 << iota
+	Monday
+	Tuesday
+	Wednesday
+	Thursday
+	Friday
+	Saturday
+)
+
+// Has reports whether day is set in w.
+func (w Weekdays) Has(day time.Weekday) bool {
+	return w&(1<<uint(day)) != 0
+}
+
+// ChargingSchedule describes a recurring window, identified by a
+// time-of-day range and a set of weekdays, during which the vehicle
+// should charge.  It's the main knob Leaf owners have for avoiding
+// peak electricity rates.
+type ChargingSchedule struct {
+	// Start is the time of day charging should begin, as an offset
+	// from midnight.
+	Start time.Duration
+
+	// End is the time of day charging should stop, as an offset
+	// from midnight.  If it is earlier than Start, the window
+	// spans midnight.
+	End time.Duration
+
+	// Weekdays is the set of days this schedule applies to.
+	Weekdays Weekdays
+}
+
+// parseTimeOfDay parses a "15:04"-formatted time of day into a
+// time.Duration offset from midnight.
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// formatTimeOfDay formats a time.Duration offset from midnight as a
+// "15:04"-formatted time of day.
+func formatTimeOfDay(d time.Duration) string {
+	d %= 24 * time.Hour
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// ChargingSchedule returns the vehicle's currently configured daily
+// charging schedule.
+func (s *Session) ChargingSchedule() (ChargingSchedule, error) {
+	if s.backend == BackendKamereon {
+		return s.kamereonChargingSchedule()
+	}
+
+	if s.customSessionID == "" {
+		return ChargingSchedule{}, ErrNotLoggedIn
+	}
+
+	var resp struct {
+		baseResponse
+		SettingInfo struct {
+			ChargeTime1 struct {
+				Start string `json:"Start"`
+				End   string `json:"End"`
+			} `json:"ChargeTime1"`
+			Day struct {
+				Sun string `json:"Sun"`
+				Mon string `json:"Mon"`
+				Tue string `json:"Tue"`
+				Wed string `json:"Wed"`
+				Thu string `json:"Thu"`
+				Fri string `json:"Fri"`
+				Sat string `json:"Sat"`
+			} `json:"Day"`
+		} `json:"SettingInfo"`
+	}
+	if err := s.apiRequest("GetScheduledChargingDetailsRequest.php", s.commonParams(), &resp); err != nil {
+		return ChargingSchedule{}, err
+	}
+
+	start, err := parseTimeOfDay(resp.SettingInfo.ChargeTime1.Start)
+	if err != nil {
+		return ChargingSchedule{}, err
+	}
+
+	end, err := parseTimeOfDay(resp.SettingInfo.ChargeTime1.End)
+	if err != nil {
+		return ChargingSchedule{}, err
+	}
+
+	day := resp.SettingInfo.Day
+	var weekdays Weekdays
+	for _, wd := range []struct {
+		set  string
+		flag Weekdays
+	}{
+		{day.Sun, Sunday},
+		{day.Mon, Monday},
+		{day.Tue, Tuesday},
+		{day.Wed, Wednesday},
+		{day.Thu, Thursday},
+		{day.Fri, Friday},
+		{day.Sat, Saturday},
+	} {
+		if wd.set == "1" {
+			weekdays |= wd.flag
+		}
+	}
+
+	return ChargingSchedule{
+		Start:    start,
+		End:      end,
+		Weekdays: weekdays,
+	}, nil
+}
+
+// SetChargingSchedule configures the vehicle's daily charging
+// schedule.
+func (s *Session) SetChargingSchedule(cs ChargingSchedule) error {
+	if s.backend == BackendKamereon {
+		return s.kamereonSetChargingSchedule(cs)
+	}
+
+	if s.customSessionID == "" {
+		return ErrNotLoggedIn
+	}
+
+	params := s.commonParams()
+	params.Set("ChargeTime1Start", formatTimeOfDay(cs.Start))
+	params.Set("ChargeTime1End", formatTimeOfDay(cs.End))
+
+	for _, wd := range []struct {
+		name string
+		day  time.Weekday
+	}{
+		{"Sun", time.Sunday},
+		{"Mon", time.Monday},
+		{"Tue", time.Tuesday},
+		{"Wed", time.Wednesday},
+		{"Thu", time.Thursday},
+		{"Fri", time.Friday},
+		{"Sat", time.Saturday},
+	} {
+		value := "0"
+		if cs.Weekdays.Has(wd.day) {
+			value = "1"
+		}
+		params.Set(wd.name, value)
+	}
+
+	var resp baseResponse
+	return s.apiRequest("ScheduledChargingRequest.php", params, &resp)
+}
@@ -0,0 +1,53 @@
+package carwings
+
+import "fmt"
+
+// Vehicle describes one car associated with a Carwings or Kamereon
+// account.
+type Vehicle struct {
+	// VIN is the vehicle identification number, and the primary
+	// way to address a specific vehicle.
+	VIN string
+
+	// Nickname is the name the owner gave the vehicle in the
+	// NissanConnect EV / Carwings app, if any.
+	Nickname string
+
+	// ModelYear is the vehicle's model year, e.g. "2018".
+	ModelYear string
+
+	// CustomSessionID is the legacy Carwings backend's per-vehicle
+	// session token.  It is empty for vehicles on BackendKamereon.
+	CustomSessionID string
+}
+
+// Vehicles returns every vehicle on the account used to create this
+// Session.  Use SelectVehicle to change which one subsequent calls
+// such as BatteryStatus operate on.
+func (s *Session) Vehicles() []Vehicle {
+	vehicles := make([]Vehicle, len(s.vehicles))
+	copy(vehicles, s.vehicles)
+	return vehicles
+}
+
+// selectVehicle points the Session at v without validating that v
+// came from s.vehicles.
+func (s *Session) selectVehicle(v Vehicle) {
+	s.vin = v.VIN
+	s.customSessionID = v.CustomSessionID
+}
+
+// SelectVehicle changes which vehicle on the account subsequent method
+// calls, such as BatteryStatus and UpdateStatus, operate on.  It
+// returns an error if vin does not match any vehicle returned by
+// Vehicles.
+func (s *Session) SelectVehicle(vin string) error {
+	for _, v := range s.vehicles {
+		if v.VIN == vin {
+			s.selectVehicle(v)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("carwings: no vehicle with VIN %q on this account", vin)
+}